@@ -0,0 +1,172 @@
+// Package harness coordinates the terratest suite so subtests can run in
+// parallel against a single provisioned cluster instead of each
+// provisioning (and colliding over) its own. It hands out unique
+// namespaces and MetalLB IP slices per test and can reuse a
+// once-per-suite cluster when TERRATEST_SHARED_CLUSTER=1.
+package harness
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// SharedClusterEnvVar, when set to "1", tells the harness to provision
+// the k3s cluster once via Setup/TestMain and hand the same
+// terraform.Options to every subtest instead of re-applying per test.
+const SharedClusterEnvVar = "TERRATEST_SHARED_CLUSTER"
+
+// Harness owns the shared cluster (when enabled) and the pools that
+// parallel tests draw unique namespaces and MetalLB IPs from.
+type Harness struct {
+	sharedCluster bool
+
+	mu           sync.Mutex
+	clusterOpts  *terraform.Options
+	namespaceSeq int
+	ipPool       []string
+	ipPoolNext   int
+}
+
+// New builds a Harness. If TERRATEST_SHARED_CLUSTER=1, k3sOptions is
+// applied once (by Setup) and reused by every test that reads it through
+// ClusterOptions/ClusterVars; otherwise each test is expected to provision its own
+// cluster as before and the harness only hands out namespace/IP
+// isolation.
+func New(ipPool []string) *Harness {
+	return &Harness{
+		sharedCluster: os.Getenv(SharedClusterEnvVar) == "1",
+		ipPool:        ipPool,
+	}
+}
+
+// SharedCluster reports whether TERRATEST_SHARED_CLUSTER=1 was set.
+func (h *Harness) SharedCluster() bool {
+	return h.sharedCluster
+}
+
+// Setup provisions the shared k3s cluster once, intended to be called
+// from TestMain. It is a no-op when shared cluster mode is disabled.
+func (h *Harness) Setup(k3sOptions *terraform.Options) (teardown func(), err error) {
+	if !h.sharedCluster {
+		return func() {}, nil
+	}
+
+	if _, err := terraform.InitAndApplyE(nil, k3sOptions); err != nil {
+		return nil, fmt.Errorf("harness: failed to provision shared k3s cluster: %w", err)
+	}
+
+	h.mu.Lock()
+	h.clusterOpts = k3sOptions
+	h.mu.Unlock()
+
+	return func() { terraform.Destroy(nil, k3sOptions) }, nil
+}
+
+// ClusterOptions returns the shared cluster's terraform.Options. It
+// panics if called before Setup in shared cluster mode, which indicates
+// a bug in TestMain wiring rather than a condition tests should recover
+// from.
+func (h *Harness) ClusterOptions() *terraform.Options {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clusterOpts == nil {
+		panic("harness: ClusterOptions called before Setup in shared cluster mode")
+	}
+	return h.clusterOpts
+}
+
+// Namespace returns a unique, short, DNS-label-safe namespace name for
+// the given test, suffixed with an incrementing sequence number so
+// concurrent parallel subtests never collide even when they share a test
+// name prefix.
+func (h *Harness) Namespace(t *testing.T, prefix string) string {
+	h.mu.Lock()
+	h.namespaceSeq++
+	seq := h.namespaceSeq
+	h.mu.Unlock()
+
+	return fmt.Sprintf("%s-%d", prefix, seq)
+}
+
+// IPSlice hands out a unique, non-overlapping slice of count addresses
+// from the harness's MetalLB pool, formatted as a Terraform
+// "start-end" range string. It fails the test if the pool is exhausted.
+func (h *Harness) IPSlice(t *testing.T, count int) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ipPoolNext+count > len(h.ipPool) {
+		t.Fatalf("harness: MetalLB IP pool exhausted: requested %d addresses, only %d remain", count, len(h.ipPool)-h.ipPoolNext)
+	}
+
+	slice := h.ipPool[h.ipPoolNext : h.ipPoolNext+count]
+	h.ipPoolNext += count
+	return fmt.Sprintf("%s-%s", slice[0], slice[len(slice)-1])
+}
+
+// NewKubectlOptions builds KubectlOptions for namespace using the default
+// kubeconfig and its current context.
+func (h *Harness) NewKubectlOptions(namespace string) *k8s.KubectlOptions {
+	return k8s.NewKubectlOptions("", "", namespace)
+}
+
+// CreateNamespace allocates a unique namespace name from prefix (see
+// Namespace), creates it on the cluster, and registers its deletion via
+// t.Cleanup. It returns both the name and KubectlOptions scoped to it, so
+// callers have an actual isolated namespace to point resources and
+// kubectl calls at instead of a decorative unique string.
+func (h *Harness) CreateNamespace(t *testing.T, prefix string) (string, *k8s.KubectlOptions) {
+	name := h.Namespace(t, prefix)
+	opts := h.NewKubectlOptions(name)
+
+	k8s.CreateNamespace(t, opts, name)
+	t.Cleanup(func() {
+		k8s.DeleteNamespace(t, opts, name)
+	})
+
+	return name, opts
+}
+
+// ClusterVars returns the Terraform vars a dependent module should merge
+// into its own Vars to target the shared cluster instead of implicitly
+// provisioning/expecting a separate one. It returns nil when shared
+// cluster mode is disabled, since each test then provisions (and
+// destroys) its own infrastructure as before.
+func (h *Harness) ClusterVars() map[string]interface{} {
+	if !h.sharedCluster {
+		return nil
+	}
+	return map[string]interface{}{
+		"cluster_name": h.ClusterOptions().Vars["cluster_name"],
+	}
+}
+
+// ExpandIPRange enumerates every address in a "start-end" IPv4 range, for
+// building the ipPool passed to New.
+func ExpandIPRange(start, end string) []string {
+	startIP := net.ParseIP(start).To4()
+	endIP := net.ParseIP(end).To4()
+	if startIP == nil || endIP == nil {
+		panic(fmt.Sprintf("harness: invalid IPv4 range %s-%s", start, end))
+	}
+
+	var ips []string
+	for ip := ipToUint32(startIP); ip <= ipToUint32(endIP); ip++ {
+		ips = append(ips, uint32ToIP(ip).String())
+	}
+	return ips
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+func uint32ToIP(v uint32) net.IP {
+	return net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
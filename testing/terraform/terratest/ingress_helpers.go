@@ -0,0 +1,49 @@
+package terratest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/tzervas/homelab-infra/testing/terraform/terratest/resources"
+)
+
+// publishedServiceRef identifies the "namespace/name" of the Service an
+// ingress controller was configured to publish its address from (the
+// ingress-nginx "--publish-service" / Traefik "publishedService" pattern).
+type publishedServiceRef struct {
+	namespace string
+	name      string
+}
+
+// assertIngressStatusMatchesService waits for ingressName's
+// status.loadBalancer.ingress to match the published Service's own
+// LoadBalancer addresses, proving the controller propagated the address
+// from the right place rather than some stale or unrelated value. It
+// polls via ClusterResources.WaitForIngressHasTargets instead of a
+// hand-rolled retry loop.
+func assertIngressStatusMatchesService(t *testing.T, terraformOptions *terraform.Options, kubectlOptions *k8s.KubectlOptions, ingressName string, publishedService publishedServiceRef, timeout time.Duration) {
+	clusterResources := resources.NewClusterResources(t, terraformOptions, kubectlOptions)
+
+	service := clusterResources.Service(publishedService.namespace, publishedService.name)
+	require.NotEmpty(t, service.Status.LoadBalancer.Ingress, "published service %s/%s has no LoadBalancer ingress", publishedService.namespace, publishedService.name)
+
+	var expected []string
+	for _, lb := range service.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			expected = append(expected, lb.IP)
+		}
+		if lb.Hostname != "" {
+			expected = append(expected, lb.Hostname)
+		}
+	}
+
+	clusterResources.WaitForIngressHasTargets(kubectlOptions.Namespace, ingressName, expected, 5*time.Second, timeout)
+
+	// Re-fetch the typed object and check the published address is still
+	// present after the poll returned.
+	ingress := clusterResources.Ingress(kubectlOptions.Namespace, ingressName)
+	require.NotEmpty(t, ingress.Status.LoadBalancer.Ingress, "ingress %s/%s lost its published address between poll and fetch", kubectlOptions.Namespace, ingressName)
+}
@@ -2,15 +2,22 @@ package terratest
 
 import (
 	"testing"
+
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestK3sClusterModule(t *testing.T) {
+	t.Parallel()
+
+	if networkingHarness.SharedCluster() {
+		t.Skip("cluster already provisioned once via TestMain (TERRATEST_SHARED_CLUSTER=1); see harness.Setup")
+	}
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../../terraform/modules/k3s-cluster",
 		Vars: map[string]interface{}{
-			"cluster_name": "terratest-k3s",
+			"cluster_name": networkingHarness.Namespace(t, "terratest-k3s"),
 			"node_count":   1,
 			"environment":  "test",
 		},
@@ -0,0 +1,34 @@
+package terratest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/tzervas/homelab-infra/testing/terraform/terratest/harness"
+)
+
+// TestMain provisions the shared k3s cluster once when
+// TERRATEST_SHARED_CLUSTER=1 so every test in the suite can run in
+// parallel against it instead of each standing up its own. Outside of
+// shared cluster mode this is a no-op and every test provisions (and
+// tears down) its own infrastructure as before.
+func TestMain(m *testing.M) {
+	teardown, err := networkingHarness.Setup(&terraform.Options{
+		TerraformDir: "../../../terraform/modules/k3s-cluster",
+		Vars: map[string]interface{}{
+			"cluster_name": "terratest-shared",
+			"node_count":   1,
+			"environment":  "test",
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	teardown()
+	os.Exit(code)
+}
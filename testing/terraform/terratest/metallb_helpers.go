@@ -0,0 +1,87 @@
+package terratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+// metalLBMode is the MetalLB announcement mode under test, exposed to
+// Terraform as the metallb_mode variable.
+type metalLBMode string
+
+const (
+	metalLBModeLayer2 metalLBMode = "layer2"
+	metalLBModeBGP    metalLBMode = "bgp"
+)
+
+// getEndpointSlices lists the EndpointSlices (discovery.k8s.io/v1) for
+// the named Service. Kubernetes >=1.21 always maintains these alongside
+// (or instead of) the legacy Endpoints object, and they carry each
+// endpoint's actual Ready condition, so tests can tell "pod is Ready"
+// apart from "pod is actually serving traffic".
+func getEndpointSlices(t *testing.T, kubectlOptions *k8s.KubectlOptions, serviceName string) []discoveryv1.EndpointSlice {
+	out := k8s.RunKubectlAndGetOutput(t, kubectlOptions, "get", "endpointslices",
+		"-l", fmt.Sprintf("kubernetes.io/service-name=%s", serviceName), "-o", "json")
+
+	var list discoveryv1.EndpointSliceList
+	require.NoError(t, json.Unmarshal([]byte(out), &list), "failed to parse EndpointSlices for service %s/%s", kubectlOptions.Namespace, serviceName)
+	return list.Items
+}
+
+// assertServiceHasReadyEndpoints fails the test unless at least one
+// address across all of serviceName's EndpointSlices is actually marked
+// Ready, catching the case where a rollout reports Available but the
+// backing pods aren't serving (e.g. readiness gate stuck, slow CNI
+// programming).
+func assertServiceHasReadyEndpoints(t *testing.T, kubectlOptions *k8s.KubectlOptions, serviceName string) {
+	slices := getEndpointSlices(t, kubectlOptions, serviceName)
+	require.NotEmpty(t, slices, "service %s/%s has no EndpointSlices", kubectlOptions.Namespace, serviceName)
+
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && *endpoint.Conditions.Ready {
+				return
+			}
+		}
+	}
+	t.Fatalf("service %s/%s has EndpointSlices but no Ready endpoints", kubectlOptions.Namespace, serviceName)
+}
+
+// assertLayer2AddressReachable fetches the MetalLB-assigned LoadBalancer IP with wget
+// from an in-cluster pod, proving the speaker actually answered ARP for
+// it and is routing traffic, not merely that the Service object carries
+// the IP.
+func assertLayer2AddressReachable(t *testing.T, kubectlOptions *k8s.KubectlOptions, probePod, lbIP string, port int) {
+	out, err := k8s.RunKubectlAndGetOutputE(t, kubectlOptions, "exec", probePod, "--",
+		"wget", "-q", "-T", "5", "-O-", fmt.Sprintf("http://%s:%d", lbIP, port))
+	require.NoError(t, err, "could not reach MetalLB layer2 address %s:%d from %s: %s", lbIP, port, probePod, out)
+}
+
+// bgpPeerStatus mirrors the shape of the metallb_bgp_peer_status
+// Terraform output: one entry per configured peer, reporting whether a
+// session is established and which pool routes it has advertised.
+type bgpPeerStatus struct {
+	PeerAddress string   `json:"peer_address"`
+	Established bool     `json:"established"`
+	Advertised  []string `json:"advertised_routes"`
+}
+
+// assertBGPSessionEstablished fails the test unless the mock goBGP peer
+// fixture reports an established session with the MetalLB speaker and
+// has the expected pool CIDR among its advertised routes.
+func assertBGPSessionEstablished(t *testing.T, statuses []bgpPeerStatus, expectedPeer, expectedRoute string) {
+	for _, status := range statuses {
+		if status.PeerAddress != expectedPeer {
+			continue
+		}
+		require.True(t, status.Established, "BGP peer %s session is not established", expectedPeer)
+		require.Contains(t, status.Advertised, expectedRoute, "BGP peer %s did not advertise route %s", expectedPeer, expectedRoute)
+		return
+	}
+	t.Fatalf("no BGP peer status reported for %s", expectedPeer)
+}
@@ -0,0 +1,147 @@
+package terratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// GetNetworkPolicy fetches the named NetworkPolicy and unmarshals it into
+// the typed networking/v1 object, failing the test on any kubectl or
+// decode error.
+func GetNetworkPolicy(t *testing.T, kubectlOptions *k8s.KubectlOptions, name string) *networkingv1.NetworkPolicy {
+	out := k8s.RunKubectlAndGetOutput(t, kubectlOptions, "get", "networkpolicy", name, "-o", "json")
+
+	var policy networkingv1.NetworkPolicy
+	require.NoError(t, json.Unmarshal([]byte(out), &policy), "failed to parse NetworkPolicy %s/%s", kubectlOptions.Namespace, name)
+	return &policy
+}
+
+// trafficProbePodManifest is a minimal busybox pod used by
+// AssertNetworkPolicyEnforced (and the MetalLB layer2 reachability check)
+// as the client side of a connectivity probe. It does not listen on
+// anything.
+const trafficProbePodManifest = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+%s
+spec:
+  restartPolicy: Never
+  containers:
+  - name: probe
+    image: busybox:1.36
+    command: ["sleep", "3600"]
+`
+
+// trafficServerPodManifest is a busybox pod that actually serves HTTP on
+// port, so a NetworkPolicy test has a live listener to probe instead of
+// a connection that fails regardless of enforcement.
+const trafficServerPodManifest = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+%s
+spec:
+  restartPolicy: Never
+  containers:
+  - name: server
+    image: busybox:1.36
+    command: ["sh", "-c", "echo ok > /tmp/index.html && exec busybox httpd -f -h /tmp -p %d"]
+    ports:
+    - containerPort: %d
+`
+
+// deriveNetworkPolicyTestLabels inspects policy's actual podSelector and
+// ingress rules and returns the labels needed to exercise it for real:
+// toLabels for a destination pod the policy applies to, allowedFromLabels
+// for a source pod the first ingress podSelector permits, and
+// deniedFromLabels for a source pod whose label values differ from that
+// selector's (other ingress rules of the policy are not checked against
+// it). Using labels pulled from the policy itself (rather than
+// invented ones) guarantees the probe actually exercises this policy's
+// enforcement instead of an unrelated guess.
+func deriveNetworkPolicyTestLabels(t *testing.T, policy *networkingv1.NetworkPolicy) (toLabels, allowedFromLabels, deniedFromLabels map[string]string) {
+	require.NotEmpty(t, policy.Spec.PodSelector.MatchLabels, "policy %s has no podSelector.matchLabels to target a destination pod with", policy.Name)
+	toLabels = policy.Spec.PodSelector.MatchLabels
+
+	var fromSelector map[string]string
+	for _, rule := range policy.Spec.Ingress {
+		for _, peer := range rule.From {
+			if peer.PodSelector != nil && len(peer.PodSelector.MatchLabels) > 0 {
+				fromSelector = peer.PodSelector.MatchLabels
+				break
+			}
+		}
+		if fromSelector != nil {
+			break
+		}
+	}
+	require.NotEmpty(t, fromSelector, "policy %s has no ingress[].from[].podSelector.matchLabels to derive an allowed source from", policy.Name)
+
+	allowedFromLabels = fromSelector
+	deniedFromLabels = make(map[string]string, len(fromSelector))
+	for k, v := range fromSelector {
+		deniedFromLabels[k] = v + "-terratest-denied"
+	}
+	return toLabels, allowedFromLabels, deniedFromLabels
+}
+
+// AssertNetworkPolicyEnforced deploys two ephemeral busybox pods — a
+// client carrying fromLabels and a server carrying toLabels that actually
+// listens on port — in the given namespaces, then execs a `wget` from
+// the client against the server's IP and asserts the connection succeeds
+// or times out according to shouldAllow. This exercises the CNI's actual
+// policy enforcement (Calico/Cilium) rather than merely checking the
+// NetworkPolicy object exists.
+func AssertNetworkPolicyEnforced(t *testing.T, kubectlOptions *k8s.KubectlOptions, policyName string, fromNamespace string, fromLabels map[string]string, toNamespace string, toLabels map[string]string, port int, shouldAllow bool) {
+	fromOpts := k8s.NewKubectlOptions(kubectlOptions.ContextName, kubectlOptions.ConfigPath, fromNamespace)
+	toOpts := k8s.NewKubectlOptions(kubectlOptions.ContextName, kubectlOptions.ConfigPath, toNamespace)
+
+	fromPod := fmt.Sprintf("%s-netpol-src", policyName)
+	toPod := fmt.Sprintf("%s-netpol-dst", policyName)
+
+	fromManifest := fmt.Sprintf(trafficProbePodManifest, fromPod, fromNamespace, labelsToYAML(fromLabels))
+	toManifest := fmt.Sprintf(trafficServerPodManifest, toPod, toNamespace, labelsToYAML(toLabels), port, port)
+
+	k8s.KubectlApplyFromString(t, fromOpts, fromManifest)
+	defer k8s.KubectlDeleteFromString(t, fromOpts, fromManifest)
+	k8s.KubectlApplyFromString(t, toOpts, toManifest)
+	defer k8s.KubectlDeleteFromString(t, toOpts, toManifest)
+
+	k8s.WaitUntilPodAvailable(t, fromOpts, fromPod, 30, 5*time.Second)
+	k8s.WaitUntilPodAvailable(t, toOpts, toPod, 30, 5*time.Second)
+
+	destPod := k8s.GetPod(t, toOpts, toPod)
+	destAddr := fmt.Sprintf("%s:%d", destPod.Status.PodIP, port)
+
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("probe %s -> %s", fromPod, destAddr), 3, 5*time.Second, func() (string, error) {
+		return k8s.RunKubectlAndGetOutputE(t, fromOpts, "exec", fromPod, "--", "wget", "-q", "-T", "3", "-O-", fmt.Sprintf("http://%s", destAddr))
+	})
+
+	if shouldAllow {
+		require.NoError(t, err, "policy %s should allow traffic from %s/%s to %s/%s:%d but it was blocked", policyName, fromNamespace, fromPod, toNamespace, toPod, port)
+	} else {
+		require.Error(t, err, "policy %s should block traffic from %s/%s to %s/%s:%d but it succeeded", policyName, fromNamespace, fromPod, toNamespace, toPod, port)
+	}
+}
+
+func labelsToYAML(labels map[string]string) string {
+	out := ""
+	for k, v := range labels {
+		out += fmt.Sprintf("    %s: %q\n", k, v)
+	}
+	return out
+}
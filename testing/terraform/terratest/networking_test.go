@@ -1,6 +1,8 @@
 package terratest
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,39 +11,75 @@ import (
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tzervas/homelab-infra/testing/terraform/terratest/harness"
+	"github.com/tzervas/homelab-infra/testing/terraform/terratest/resources"
 )
 
+// networkingHarness hands out unique namespaces and MetalLB IP slices to
+// every networking test so they can run concurrently without colliding,
+// whether or not they share a single provisioned cluster (see
+// harness.SharedClusterEnvVar).
+var networkingHarness = harness.New(harness.ExpandIPRange("192.168.100.200", "192.168.100.250"))
+
+// TestNetworkingModule runs the networking module's test suite once per
+// MetalLB announcement mode: layer2 (ARP) and bgp (session with a mock
+// goBGP peer fixture). Each mode gets its own Terraform apply and its own
+// IP slice/namespaces from networkingHarness so the two can run in
+// parallel without stepping on each other's MetalLB/ingress-nginx
+// installs or test workloads.
 func TestNetworkingModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"cluster_name":     "terratest-networking",
-			"environment":      "test",
-			"metallb_ip_range": "192.168.100.200-192.168.100.250",
-			"enable_ingress":   true,
-		},
-		NoColor: true,
+	modes := []metalLBMode{metalLBModeLayer2, metalLBModeBGP}
+	for _, mode := range modes {
+		mode := mode
+		t.Run(string(mode), func(t *testing.T) {
+			t.Parallel()
+
+			ipRange := networkingHarness.IPSlice(t, 10)
+			metalLBNamespace, metalLBOpts := networkingHarness.CreateNamespace(t, "metallb-system")
+			ingressNamespace, ingressOpts := networkingHarness.CreateNamespace(t, "ingress-nginx")
+			testNamespace, testOpts := networkingHarness.CreateNamespace(t, "netpol")
+
+			vars := map[string]interface{}{
+				"cluster_name":      networkingHarness.Namespace(t, "terratest-networking"),
+				"environment":       "test",
+				"metallb_ip_range":  ipRange,
+				"metallb_mode":      string(mode),
+				"metallb_namespace": metalLBNamespace,
+				"ingress_namespace": ingressNamespace,
+				"policy_namespace":  testNamespace,
+				"enable_ingress":    true,
+			}
+			for k, v := range networkingHarness.ClusterVars() {
+				vars[k] = v
+			}
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../../terraform/modules/networking",
+				Vars:         vars,
+				NoColor:      true,
+			}
+
+			defer terraform.Destroy(t, terraformOptions)
+
+			terraform.InitAndPlan(t, terraformOptions)
+			terraform.Apply(t, terraformOptions)
+
+			// Test MetalLB deployment
+			testMetalLBDeployment(t, terraformOptions, mode, metalLBOpts, testOpts)
+
+			// Test Ingress controller
+			testIngressController(t, terraformOptions, ingressOpts)
+
+			// Test network policies
+			testNetworkPolicies(t, terraformOptions, testOpts)
+		})
 	}
-
-	defer terraform.Destroy(t, terraformOptions)
-
-	terraform.InitAndPlan(t, terraformOptions)
-	terraform.Apply(t, terraformOptions)
-
-	// Test MetalLB deployment
-	testMetalLBDeployment(t, terraformOptions)
-
-	// Test Ingress controller
-	testIngressController(t, terraformOptions)
-
-	// Test network policies
-	testNetworkPolicies(t, terraformOptions)
 }
 
-func testMetalLBDeployment(t *testing.T, terraformOptions *terraform.Options) {
-	kubectlOptions := k8s.NewKubectlOptions("", "", "metallb-system")
+func testMetalLBDeployment(t *testing.T, terraformOptions *terraform.Options, mode metalLBMode, kubectlOptions, probeOptions *k8s.KubectlOptions) {
+	clusterResources := resources.NewClusterResources(t, terraformOptions, kubectlOptions)
 
 	// Wait for MetalLB controller to be available
 	retry.DoWithRetry(t, "Wait for MetalLB controller", 30, 10*time.Second, func() (string, error) {
@@ -52,11 +90,41 @@ func testMetalLBDeployment(t *testing.T, terraformOptions *terraform.Options) {
 	daemonSet := k8s.GetDaemonSet(t, kubectlOptions, "speaker")
 	require.NotNil(t, daemonSet)
 	assert.Equal(t, "speaker", daemonSet.Name)
-}
 
-func testIngressController(t *testing.T, terraformOptions *terraform.Options) {
-	kubectlOptions := k8s.NewKubectlOptions("", "", "ingress-nginx")
+	// Ready pods aren't the same as pods actually serving traffic -
+	// discover the controller's endpoints via EndpointSlices so we catch
+	// the gap.
+	assertServiceHasReadyEndpoints(t, kubectlOptions, "controller")
+
+	// The address pool backing this mode's assignment should exist
+	// regardless of layer2 vs bgp.
+	pool := clusterResources.MetalLBAddressPool(kubectlOptions.Namespace)
+	assert.NotEmpty(t, pool["spec"], "metallb IPAddressPool %v has no spec", pool)
+
+	switch mode {
+	case metalLBModeLayer2:
+		lbIP := terraform.Output(t, terraformOptions, "metallb_assigned_ip")
+		probePod := networkingHarness.Namespace(t, "terratest-l2-probe")
+		probeManifest := fmt.Sprintf(trafficProbePodManifest, probePod, probeOptions.Namespace, "    app: terratest-l2-probe\n")
+		k8s.KubectlApplyFromString(t, probeOptions, probeManifest)
+		defer k8s.KubectlDeleteFromString(t, probeOptions, probeManifest)
+		k8s.WaitUntilPodAvailable(t, probeOptions, probePod, 30, 5*time.Second)
+
+		assertLayer2AddressReachable(t, probeOptions, probePod, lbIP, 80)
+
+	case metalLBModeBGP:
+		encoded, err := json.Marshal(clusterResources.TerraformOutput("metallb_bgp_peer_status"))
+		require.NoError(t, err)
+		var statuses []bgpPeerStatus
+		require.NoError(t, json.Unmarshal(encoded, &statuses))
+
+		peerAddress := terraform.Output(t, terraformOptions, "bgp_peer_address")
+		poolCIDR := terraform.Output(t, terraformOptions, "metallb_ip_pool_cidr")
+		assertBGPSessionEstablished(t, statuses, peerAddress, poolCIDR)
+	}
+}
 
+func testIngressController(t *testing.T, terraformOptions *terraform.Options, kubectlOptions *k8s.KubectlOptions) {
 	// Check if ingress is enabled
 	ingressEnabled := terraform.Output(t, terraformOptions, "ingress_enabled")
 	if ingressEnabled != "true" {
@@ -72,31 +140,96 @@ func testIngressController(t *testing.T, terraformOptions *terraform.Options) {
 	service := k8s.GetService(t, kubectlOptions, "ingress-nginx-controller")
 	require.NotNil(t, service)
 	assert.NotEmpty(t, service.Status.LoadBalancer.Ingress)
+
+	// Deploy a sample Ingress, namespaced per-test so concurrent runs
+	// don't collide, and confirm the controller publishes the
+	// MetalLB-assigned address onto its status, not just the Service's.
+	ingressName := networkingHarness.Namespace(t, "terratest-sample-ingress")
+	manifest := sampleIngressManifest(ingressName, kubectlOptions.Namespace)
+	k8s.KubectlApplyFromString(t, kubectlOptions, manifest)
+	defer k8s.KubectlDeleteFromString(t, kubectlOptions, manifest)
+
+	assertIngressStatusMatchesService(t, terraformOptions, kubectlOptions, ingressName, publishedServiceRef{
+		namespace: kubectlOptions.Namespace,
+		name:      "ingress-nginx-controller",
+	}, 3*time.Minute)
+}
+
+func sampleIngressManifest(name, namespace string) string {
+	return fmt.Sprintf(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  ingressClassName: nginx
+  rules:
+  - host: %s.local
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: ingress-nginx-controller
+            port:
+              number: 80
+`, name, namespace, name)
 }
 
-func testNetworkPolicies(t *testing.T, terraformOptions *terraform.Options) {
-	kubectlOptions := k8s.NewKubectlOptions("", "", "default")
+func testNetworkPolicies(t *testing.T, terraformOptions *terraform.Options, kubectlOptions *k8s.KubectlOptions) {
+	clusterResources := resources.NewClusterResources(t, terraformOptions, kubectlOptions)
 
 	// Test network policy creation
 	networkPolicyCount := terraform.Output(t, terraformOptions, "network_policy_count")
 	assert.NotEmpty(t, networkPolicyCount)
 
-	// Verify at least one network policy exists
-	policies, err := k8s.RunKubectlAndGetOutputE(t, kubectlOptions, "get", "networkpolicies", "-o", "json")
-	require.NoError(t, err)
-	assert.Contains(t, policies, "NetworkPolicy")
+	// Wait for the module's primary NetworkPolicy to actually be applied
+	// rather than racing a plain list/get against the apply.
+	policyName := terraform.Output(t, terraformOptions, "network_policy_name")
+	clusterResources.WaitForNetworkPolicyApplied(kubectlOptions.Namespace, policyName, 5*time.Second, 2*time.Minute)
+
+	// Verify at least one network policy exists and inspect it directly
+	// instead of pattern-matching raw kubectl output.
+	policies := clusterResources.NetworkPolicies(kubectlOptions.Namespace)
+	require.NotEmpty(t, policies)
+
+	policy := GetNetworkPolicy(t, kubectlOptions, policyName)
+	assert.NotEmpty(t, policy.Spec.PolicyTypes)
+
+	// Verify the policy actually enforces what it claims, using labels
+	// pulled from its own podSelector/ingress rules rather than invented
+	// ones: a source matching an allowed rule gets through, one that
+	// doesn't is blocked.
+	toLabels, allowedFromLabels, deniedFromLabels := deriveNetworkPolicyTestLabels(t, policy)
+
+	AssertNetworkPolicyEnforced(t, kubectlOptions, policy.Name+"-allow",
+		kubectlOptions.Namespace, allowedFromLabels,
+		kubectlOptions.Namespace, toLabels,
+		80, true)
+
+	AssertNetworkPolicyEnforced(t, kubectlOptions, policy.Name+"-deny",
+		kubectlOptions.Namespace, deniedFromLabels,
+		kubectlOptions.Namespace, toLabels,
+		80, false)
 }
 
 func TestNetworkingModuleWithCustomConfig(t *testing.T) {
 	t.Parallel()
 
+	vars := map[string]interface{}{
+		"cluster_name": "terratest-custom-network",
+		"environment":  "test",
+	}
+	for k, v := range networkingHarness.ClusterVars() {
+		vars[k] = v
+	}
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../../terraform/modules/networking",
 		VarFiles:     []string{"../../../config/terraform/test.tfvars"},
-		Vars: map[string]interface{}{
-			"cluster_name": "terratest-custom-network",
-			"environment":  "test",
-		},
+		Vars:         vars,
 	}
 
 	defer terraform.Destroy(t, terraformOptions)
@@ -0,0 +1,169 @@
+// Package resources provides a typed abstraction over the Terraform +
+// Kubernetes state produced by a terratest run, so integration tests can
+// assert on real objects instead of shelling out to kubectl and matching
+// on raw JSON/string output.
+package resources
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// ClusterResources is a handle on the Terraform-provisioned and
+// Kubernetes-deployed state for a single test, giving fluent, typed
+// access to the resources a test cares about instead of repeating
+// kubectl/terraform plumbing in every test function.
+type ClusterResources struct {
+	t                *testing.T
+	terraformOptions *terraform.Options
+	kubectlOptions   *k8s.KubectlOptions
+}
+
+// NewClusterResources builds a ClusterResources handle from the
+// terraform.Options used to provision a module and the KubectlOptions
+// pointing at the resulting cluster.
+func NewClusterResources(t *testing.T, terraformOptions *terraform.Options, kubectlOptions *k8s.KubectlOptions) *ClusterResources {
+	return &ClusterResources{
+		t:                t,
+		terraformOptions: terraformOptions,
+		kubectlOptions:   kubectlOptions,
+	}
+}
+
+// Ingress fetches the named Ingress in namespace.
+func (r *ClusterResources) Ingress(namespace, name string) *networkingv1.Ingress {
+	return k8s.GetIngress(r.t, r.namespaced(namespace), name)
+}
+
+// Service fetches the named Service in namespace.
+func (r *ClusterResources) Service(namespace, name string) *corev1.Service {
+	return k8s.GetService(r.t, r.namespaced(namespace), name)
+}
+
+// NetworkPolicies lists every NetworkPolicy in namespace.
+func (r *ClusterResources) NetworkPolicies(namespace string) []networkingv1.NetworkPolicy {
+	opts := r.namespaced(namespace)
+	out := k8s.RunKubectlAndGetOutput(r.t, opts, "get", "networkpolicies", "-o", "json")
+
+	var list networkingv1.NetworkPolicyList
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		r.t.Fatalf("failed to parse NetworkPolicy list in namespace %s: %v", opts.Namespace, err)
+	}
+	return list.Items
+}
+
+// MetalLBAddressPool returns the first MetalLB IPAddressPool found in
+// namespace.
+func (r *ClusterResources) MetalLBAddressPool(namespace string) map[string]interface{} {
+	opts := r.namespaced(namespace)
+	out := k8s.RunKubectlAndGetOutput(r.t, opts, "get", "ipaddresspools.metallb.io", "-o", "json")
+
+	var list struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		r.t.Fatalf("failed to parse metallb IPAddressPool list: %v", err)
+	}
+	if len(list.Items) == 0 {
+		r.t.Fatalf("no metallb IPAddressPool found in namespace %s", opts.Namespace)
+	}
+	return list.Items[0]
+}
+
+// TerraformOutput looks up a dotted JSON path (e.g.
+// "module.networking.metallb.ip_range") within the module's Terraform
+// outputs. Only the leading segment is resolved via terraform.OutputJson;
+// remaining segments index into the decoded JSON value.
+func (r *ClusterResources) TerraformOutput(path string) interface{} {
+	segments := strings.Split(path, ".")
+
+	raw := terraform.OutputJson(r.t, r.terraformOptions, segments[0])
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		r.t.Fatalf("failed to parse terraform output %q: %v", segments[0], err)
+	}
+
+	for _, segment := range segments[1:] {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			r.t.Fatalf("terraform output path %q: %q is not an object", path, segment)
+		}
+		value, ok = m[segment]
+		if !ok {
+			r.t.Fatalf("terraform output path %q: no such key %q", path, segment)
+		}
+	}
+	return value
+}
+
+// WaitForIngressHasTargets polls the named Ingress every interval until
+// its status.loadBalancer.ingress addresses match expected exactly,
+// failing the test if timeout elapses first.
+func (r *ClusterResources) WaitForIngressHasTargets(namespace, name string, expected []string, interval, timeout time.Duration) {
+	opts := r.namespaced(namespace)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		ingress := k8s.GetIngress(r.t, opts, name)
+		if ingressTargetsMatch(ingress, expected) {
+			return
+		}
+		if time.Now().After(deadline) {
+			r.t.Fatalf("ingress %s/%s did not reach targets %v before timeout", namespace, name, expected)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// WaitForNetworkPolicyApplied polls every interval until the named
+// NetworkPolicy exists in namespace, failing the test if timeout elapses
+// first.
+func (r *ClusterResources) WaitForNetworkPolicyApplied(namespace, name string, interval, timeout time.Duration) {
+	opts := r.namespaced(namespace)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if _, err := k8s.RunKubectlAndGetOutputE(r.t, opts, "get", "networkpolicy", name); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			r.t.Fatalf("network policy %s/%s was not applied before timeout", namespace, name)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (r *ClusterResources) namespaced(namespace string) *k8s.KubectlOptions {
+	if namespace == "" {
+		return r.kubectlOptions
+	}
+	return k8s.NewKubectlOptions(r.kubectlOptions.ContextName, r.kubectlOptions.ConfigPath, namespace)
+}
+
+func ingressTargetsMatch(ingress *networkingv1.Ingress, expected []string) bool {
+	got := make(map[string]bool, len(expected))
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			got[lb.IP] = true
+		}
+		if lb.Hostname != "" {
+			got[lb.Hostname] = true
+		}
+	}
+	if len(got) != len(expected) {
+		return false
+	}
+	for _, target := range expected {
+		if !got[target] {
+			return false
+		}
+	}
+	return true
+}